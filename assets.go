@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// defaultAssetPattern and defaultSignaturePattern reproduce the hard-coded
+// matching downloadReleaseAssets used before AssetPattern/SignaturePattern
+// existed: a single archive and a single ".minisig" file, named after the
+// repo, with no OS/arch discrimination. Targets that don't set their own
+// patterns keep behaving exactly as before.
+const (
+	defaultAssetPattern     = `^{{.Repo}}-[\w.]+\.(tar\.(gz|xz|zst|bz2)|zip)$`
+	defaultSignaturePattern = `^{{.Repo}}-[\w.]+\.minisig$`
+)
+
+// assetPatternData is the set of fields an AssetPattern/SignaturePattern
+// template may reference. Values are regexp.QuoteMeta'd before the template
+// is rendered, since the result is compiled as a regex: an upstream-supplied
+// Tag or Repo containing metacharacters should match literally, not widen
+// the pattern.
+type assetPatternData struct {
+	GOOS   string
+	GOARCH string
+	Tag    string
+	Repo   string
+}
+
+// selectAsset renders pattern once per candidate (GOOS, GOARCH) pair -
+// runtime.GOOS/GOARCH plus whatever the target's OSAliases/ArchAliases add
+// for them - and returns the first release asset matching any of the
+// resulting regexes. Patterns that don't reference {{.GOOS}}/{{.GOARCH}}
+// render identically for every candidate, so this degrades cleanly to a
+// single plain-regex match.
+func selectAsset(pattern string, target *Target, tag string, assets []Asset) (*Asset, error) {
+	osCandidates := append([]string{runtime.GOOS}, target.OSAliases[runtime.GOOS]...)
+	archCandidates := append([]string{runtime.GOARCH}, target.ArchAliases[runtime.GOARCH]...)
+
+	var regexes []*regexp.Regexp
+	for _, goos := range osCandidates {
+		for _, goarch := range archCandidates {
+			data := assetPatternData{
+				GOOS:   regexp.QuoteMeta(goos),
+				GOARCH: regexp.QuoteMeta(goarch),
+				Tag:    regexp.QuoteMeta(tag),
+				Repo:   regexp.QuoteMeta(target.Repo),
+			}
+			rendered, err := renderAssetPattern(pattern, data)
+			if err != nil {
+				return nil, fmt.Errorf("asset pattern %q: %v", pattern, err)
+			}
+			re, err := regexp.Compile(rendered)
+			if err != nil {
+				return nil, fmt.Errorf("asset pattern %q rendered to invalid regex %q: %v", pattern, rendered, err)
+			}
+			regexes = append(regexes, re)
+		}
+	}
+
+	for i := range assets {
+		for _, re := range regexes {
+			if re.MatchString(assets[i].Name) {
+				return &assets[i], nil
+			}
+		}
+	}
+
+	names := make([]string, len(assets))
+	for i, asset := range assets {
+		names[i] = asset.Name
+	}
+	return nil, fmt.Errorf("no asset matched pattern %q (candidate assets: %s)", pattern, strings.Join(names, ", "))
+}
+
+func renderAssetPattern(pattern string, data assetPatternData) (string, error) {
+	tmpl, err := template.New("asset-pattern").Parse(pattern)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestSelectAssetQuotesTagMetacharacters(t *testing.T) {
+	target := &Target{Name: "myapp", Repo: "myapp"}
+	assets := []Asset{
+		{Name: "myapp-1x0.tar.gz"},
+		{Name: "myapp-1.0.tar.gz"},
+	}
+
+	// Without quoting, the "." in "1.0" would match any character, so
+	// "myapp-1x0.tar.gz" would also match a pattern built around tag "1.0".
+	asset, err := selectAsset(`^{{.Repo}}-{{.Tag}}\.tar\.gz$`, target, "1.0", assets)
+	if err != nil {
+		t.Fatalf("selectAsset() = %v, want a match", err)
+	}
+	if asset.Name != "myapp-1.0.tar.gz" {
+		t.Errorf("selectAsset() matched %q, want \"myapp-1.0.tar.gz\"", asset.Name)
+	}
+}
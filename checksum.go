@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// defaultChecksumManifestPattern is the pattern used to pick out the
+// checksums file when a Target's SignatureMode is "manifest" and it hasn't
+// set ChecksumManifestPattern itself. "SHA256SUMS" is the most common name
+// upstreams use for this file; targets that publish it under a different
+// name need to set ChecksumManifestPattern explicitly.
+const defaultChecksumManifestPattern = `^SHA256SUMS$`
+
+// verifyChecksumManifest checks that manifest - the contents of a
+// SHA256SUMS-style file, one "<hex-sha256>  <filename>" line per release
+// asset - lists archiveName with a hash matching archiveBytes' actual
+// SHA-256. This lets one minisign signature over the manifest cover every
+// per-arch archive in a release, instead of signing each one individually.
+func verifyChecksumManifest(manifest []byte, archiveName string, archiveBytes []byte) error {
+	sum := sha256.Sum256(archiveBytes)
+	actualHash := hex.EncodeToString(sum[:])
+
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		hash, name := fields[0], strings.TrimPrefix(fields[1], "*")
+		if name != archiveName {
+			continue
+		}
+		if !strings.EqualFold(hash, actualHash) {
+			return fmt.Errorf("checksum mismatch for %s: manifest says %s, computed %s", archiveName, hash, actualHash)
+		}
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s not listed in checksum manifest", archiveName)
+}
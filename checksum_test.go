@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestVerifyChecksumManifest(t *testing.T) {
+	archiveBytes := []byte("archive contents")
+	sum := sha256.Sum256(archiveBytes)
+	hash := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  bool
+	}{
+		{
+			name:     "matching entry",
+			manifest: hash + "  myapp-linux-amd64.tar.gz\n",
+		},
+		{
+			name:     "matching entry with binary marker",
+			manifest: hash + " *myapp-linux-amd64.tar.gz\n",
+		},
+		{
+			name: "entry among others",
+			manifest: "deadbeef  other-file.tar.gz\n" +
+				hash + "  myapp-linux-amd64.tar.gz\n" +
+				"cafebabe  yet-another.tar.gz\n",
+		},
+		{
+			name:     "blank lines and malformed lines ignored",
+			manifest: "\n\nnotarealline\n" + hash + "  myapp-linux-amd64.tar.gz\n",
+		},
+		{
+			name:     "hash mismatch",
+			manifest: "0000000000000000000000000000000000000000000000000000000000000000  myapp-linux-amd64.tar.gz\n",
+			wantErr:  true,
+		},
+		{
+			name:     "archive not listed",
+			manifest: hash + "  some-other-archive.tar.gz\n",
+			wantErr:  true,
+		},
+		{
+			name:     "empty manifest",
+			manifest: "",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyChecksumManifest([]byte(tt.manifest), "myapp-linux-amd64.tar.gz", archiveBytes)
+			if tt.wantErr && err == nil {
+				t.Fatal("verifyChecksumManifest() = nil, want error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("verifyChecksumManifest() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestVerifyChecksumManifestHashComparisonIsCaseInsensitive(t *testing.T) {
+	archiveBytes := []byte("archive contents")
+	sum := sha256.Sum256(archiveBytes)
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	manifest := hash + "  myapp.tar.gz\n"
+	if err := verifyChecksumManifest([]byte(manifest), "myapp.tar.gz", archiveBytes); err != nil {
+		t.Fatalf("verifyChecksumManifest() = %v, want nil", err)
+	}
+}
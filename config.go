@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"regexp"
 )
 
@@ -11,12 +13,124 @@ type Config struct {
 	PublicSigningKeyFile            string    `json:"public_signing_key_file"`
 	UnsafeSkipSignatureVerification bool      `json:"unsafe_skip_signature_verification"`
 	UpdateInterval                  int       `json:"update_interval"`
+
+	// PublicSigningKey is PublicSigningKeyFile's contents, read once by
+	// loadConfig. Not part of the JSON schema; it's derived, not configured.
+	PublicSigningKey string `json:"-"`
+
+	// RetainReleases is how many extracted release directories to keep on
+	// disk per target (the current one plus however many prior ones), so
+	// `rollback` has something to point back at. Defaults to 2, matching
+	// the single-prior-release cleanup this updater always did.
+	RetainReleases int `json:"retain_releases"`
+
+	// ListenAddr, if set, starts the control/status HTTP API (see
+	// server.go) on this address instead of only polling on a timer.
+	// Mutating endpoints require a bearer token from the
+	// UPDATER_CONTROL_TOKEN environment variable, if it's set.
+	ListenAddr string `json:"listen_addr"`
+}
+
+const defaultRetainReleases = 2
+
+// loadConfig reads and validates the config file at path, applying defaults
+// for fields left unset.
+func loadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := json.Unmarshal(b, config); err != nil {
+		return nil, err
+	}
+	if config.RetainReleases == 0 {
+		config.RetainReleases = defaultRetainReleases
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !config.UnsafeSkipSignatureVerification {
+		keyBytes, err := os.ReadFile(config.PublicSigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading public signing key file: %v", err)
+		}
+		config.PublicSigningKey = string(keyBytes)
+	}
+
+	return config, nil
+}
+
+// findTarget returns the target named name, or nil if there isn't one.
+func (c *Config) findTarget(name string) *Target {
+	for _, target := range c.Targets {
+		if target.Name == name {
+			return target
+		}
+	}
+	return nil
 }
 
 type Target struct {
 	Name  string
 	Owner string
 	Repo  string
+
+	// AssetPattern and SignaturePattern are Go text/template strings,
+	// evaluated with {{.GOOS}}, {{.GOARCH}}, {{.Tag}}, and {{.Repo}}, then
+	// compiled as regexes to pick the archive and its signature out of a
+	// release's asset list. Left unset, both default to the fixed
+	// "<repo>-<anything>.<ext>" matching this updater always used.
+	AssetPattern     string
+	SignaturePattern string
+
+	// SignatureMode selects what the signature asset covers: "asset" (the
+	// default) signs the archive directly, exactly like before.
+	// "manifest" signs a checksums file instead (see ChecksumManifestPattern),
+	// listing "<sha256>  <filename>" lines; the updater re-hashes the
+	// downloaded archive and checks it against that file's entry, so one
+	// signature can vouch for every per-arch asset in a release.
+	SignatureMode           string
+	ChecksumManifestPattern string
+
+	// OSAliases and ArchAliases map a runtime.GOOS/GOARCH value to the
+	// extra spellings a release might use for it (e.g. "darwin" ->
+	// {"macos", "osx"}, "amd64" -> {"x86_64"}), so AssetPattern/
+	// SignaturePattern match regardless of which one the upstream picked.
+	OSAliases   map[string][]string
+	ArchAliases map[string][]string
+
+	// Source selects which ReleaseSource fetches this target's releases:
+	// "github" (the default), "gitlab", "gitea", or "https". BaseURL
+	// points the source at a non-default instance (a self-managed GitLab,
+	// a Gitea/Forgejo host, or the manifest URL for "https"); TokenEnv
+	// names the environment variable holding that source's credential, so
+	// multiple targets can each authenticate against a different forge.
+	Source   string
+	BaseURL  string
+	TokenEnv string
+
+	// PreDeploy and PostDeploy run around the extract-and-symlink swap;
+	// HealthCheck runs after it, attempted up to HealthCheckRetries times
+	// (1 if unset) with HealthCheckBackoffSec between attempts (2s if
+	// unset). If PostDeploy or HealthCheck never succeeds, the deploy is
+	// reverted: the symlink goes back to the previous release and the new
+	// release directory is removed.
+	PreDeploy             *CommandSpec
+	PostDeploy            *CommandSpec
+	HealthCheck           *CommandSpec
+	HealthCheckRetries    int
+	HealthCheckBackoffSec int
+}
+
+var validSources = map[string]bool{
+	"":       true,
+	"github": true,
+	"gitlab": true,
+	"gitea":  true,
+	"https":  true,
 }
 
 func (c *Config) Validate() error {
@@ -32,6 +146,9 @@ func (c *Config) Validate() error {
 	if len(c.Targets) == 0 {
 		return fmt.Errorf("at least one target must be set")
 	}
+	if c.RetainReleases < 1 {
+		return fmt.Errorf("retain_releases must be >=1")
+	}
 
 	targetNames := make(map[string]bool)
 	targetNameRegex := regexp.MustCompile(`^[\w-]+$`)
@@ -48,6 +165,15 @@ func (c *Config) Validate() error {
 		if target.Repo == "" {
 			return fmt.Errorf("repo for target %d must be set", i)
 		}
+		if !validSources[target.Source] {
+			return fmt.Errorf("target %d has unknown source %q", i, target.Source)
+		}
+		if target.Source == "https" && target.BaseURL == "" {
+			return fmt.Errorf("target %d: BaseURL must be set for the https source", i)
+		}
+		if target.SignatureMode != "" && target.SignatureMode != "asset" && target.SignatureMode != "manifest" {
+			return fmt.Errorf("target %d has unknown signature mode %q", i, target.SignatureMode)
+		}
 		if targetNames[target.Name] {
 			return fmt.Errorf("target %d has duplicate name", i)
 		}
@@ -4,23 +4,83 @@ package main
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/bzip2"
 	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
-func extractTarGz(tarGzData []byte, destination string, stripComponents int) error {
-	buf := bytes.NewBuffer(tarGzData)
-	gzipReader, err := gzip.NewReader(buf)
-	if err != nil {
-		return err
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zipMagic   = []byte{0x50, 0x4b, 0x03, 0x04}
+	xzMagic    = []byte{0xfd, 0x37, 0x7a, 0x58, 0x5a}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte{0x42, 0x5a, 0x68}
+)
+
+// extractArchive extracts an archive of unknown format into destination,
+// stripping stripComponents leading path components from each entry. The
+// format is detected from the archive's leading magic bytes rather than from
+// a file extension, since release assets are not guaranteed to be named
+// consistently. maxSize bounds the total number of decompressed bytes
+// written across all entries, guarding against zip/zstd bombs; a value of 0
+// disables the check.
+func extractArchive(data []byte, destination string, stripComponents int, maxSize int64) error {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		return extractTar(gzipReader, destination, stripComponents, maxSize)
+
+	case bytes.HasPrefix(data, zipMagic):
+		return extractZip(data, destination, stripComponents, maxSize)
+
+	case bytes.HasPrefix(data, xzMagic):
+		xzReader, err := xz.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		return extractTar(xzReader, destination, stripComponents, maxSize)
+
+	case bytes.HasPrefix(data, zstdMagic):
+		zstdReader, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer zstdReader.Close()
+		return extractTar(zstdReader, destination, stripComponents, maxSize)
+
+	case bytes.HasPrefix(data, bzip2Magic):
+		return extractTar(bzip2.NewReader(bytes.NewReader(data)), destination, stripComponents, maxSize)
+
+	default:
+		n := len(data)
+		if n > 8 {
+			n = 8
+		}
+		return fmt.Errorf("unrecognized archive format (first bytes: % x)", data[:n])
 	}
-	defer gzipReader.Close()
-	tarReader := tar.NewReader(gzipReader)
+}
+
+// extractTar walks a tar stream already decompressed from any of the
+// supported container formats, applying the same path-stripping and
+// unsupported-typeflag rejection that extractTarGz used to apply directly to
+// gzip'd tar data. Absolute paths and ".." traversal are rejected by the
+// tar package itself via the tarinsecurepath=0 debug setting above.
+func extractTar(r io.Reader, destination string, stripComponents int, maxSize int64) error {
+	tarReader := tar.NewReader(r)
+	var written int64
 
 	for {
 		header, err := tarReader.Next()
@@ -35,36 +95,26 @@ func extractTarGz(tarGzData []byte, destination string, stripComponents int) err
 			continue
 		}
 
-		// Calculate the target path by stripping components
-		target := header.Name
-		if stripComponents > 0 {
-			components := strings.SplitN(target, string(filepath.Separator), stripComponents+1)
-			if len(components) > stripComponents {
-				target = strings.Join(components[stripComponents:], string(filepath.Separator))
-			} else {
-				target = ""
-			}
+		target := stripPathComponents(header.Name, stripComponents)
+		if target == "" {
+			continue
 		}
-
-		// Get the full path for the file
 		target = filepath.Join(destination, target)
 
 		switch header.Typeflag {
 		case tar.TypeDir:
-			// Create directory if it doesn't exist
 			if err := os.MkdirAll(target, os.ModePerm); err != nil {
 				return err
 			}
 
 		case tar.TypeReg:
-			// Create file
-			file, err := os.Create(target)
-			if err != nil {
-				return err
+			if maxSize > 0 {
+				written += header.Size
+				if written > maxSize {
+					return fmt.Errorf("archive exceeds maximum allowed size of %d bytes", maxSize)
+				}
 			}
-			defer file.Close()
-
-			if _, err := io.Copy(file, tarReader); err != nil {
+			if err := writeFile(target, tarReader); err != nil {
 				return err
 			}
 
@@ -75,3 +125,80 @@ func extractTarGz(tarGzData []byte, destination string, stripComponents int) err
 
 	return nil
 }
+
+// extractZip extracts a zip archive, rejecting entries that would escape
+// destination via an absolute path or ".." and entries that aren't plain
+// files or directories (e.g. symlinks), mirroring the protections
+// tarinsecurepath=0 gives the tar-based formats above.
+func extractZip(data []byte, destination string, stripComponents int, maxSize int64) error {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	var written int64
+	for _, f := range zipReader.File {
+		if filepath.IsAbs(f.Name) || strings.Contains(f.Name, "..") {
+			return fmt.Errorf("zip entry has unsafe path: %v", f.Name)
+		}
+		if !f.Mode().IsRegular() && !f.Mode().IsDir() {
+			return fmt.Errorf("unsupported file type: %v in %v", f.Mode().Type(), f.Name)
+		}
+
+		target := stripPathComponents(f.Name, stripComponents)
+		if target == "" {
+			continue
+		}
+		target = filepath.Join(destination, target)
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if maxSize > 0 {
+			written += int64(f.UncompressedSize64)
+			if written > maxSize {
+				return fmt.Errorf("archive exceeds maximum allowed size of %d bytes", maxSize)
+			}
+		}
+
+		if err := extractZipFile(f, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractZipFile(f *zip.File, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return writeFile(target, rc)
+}
+
+func writeFile(target string, r io.Reader) error {
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, r)
+	return err
+}
+
+func stripPathComponents(name string, stripComponents int) string {
+	if stripComponents <= 0 {
+		return name
+	}
+	components := strings.SplitN(name, string(filepath.Separator), stripComponents+1)
+	if len(components) <= stripComponents {
+		return ""
+	}
+	return strings.Join(components[stripComponents:], string(filepath.Separator))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildZip returns the bytes of a zip archive containing one entry named
+// name with the given contents.
+func buildZip(t *testing.T, name string, contents []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create(name)
+	if err != nil {
+		t.Fatalf("creating zip entry: %v", err)
+	}
+	if _, err := f.Write(contents); err != nil {
+		t.Fatalf("writing zip entry: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing zip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractZipRejectsTraversal(t *testing.T) {
+	data := buildZip(t, "../evil.txt", []byte("pwned"))
+	err := extractArchive(data, t.TempDir(), 0, 0)
+	if err == nil || !strings.Contains(err.Error(), "unsafe path") {
+		t.Fatalf("extractArchive() = %v, want unsafe path error", err)
+	}
+}
+
+func TestExtractZipRejectsAbsolutePath(t *testing.T) {
+	data := buildZip(t, "/etc/passwd", []byte("pwned"))
+	err := extractArchive(data, t.TempDir(), 0, 0)
+	if err == nil || !strings.Contains(err.Error(), "unsafe path") {
+		t.Fatalf("extractArchive() = %v, want unsafe path error", err)
+	}
+}
+
+func TestExtractArchiveRejectsOversized(t *testing.T) {
+	data := buildZip(t, "file.txt", []byte("more than one byte"))
+	err := extractArchive(data, t.TempDir(), 0, 1)
+	if err == nil || !strings.Contains(err.Error(), "exceeds maximum allowed size") {
+		t.Fatalf("extractArchive() = %v, want size limit error", err)
+	}
+}
+
+func TestExtractZipWritesFile(t *testing.T) {
+	data := buildZip(t, "file.txt", []byte("hello"))
+	dest := t.TempDir()
+	if err := extractArchive(data, dest, 0, 0); err != nil {
+		t.Fatalf("extractArchive() = %v, want nil", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	if err != nil {
+		t.Fatalf("reading extracted file: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Fatalf("extracted contents = %q, want %q", got, "hello")
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CommandSpec is an argv command the updater runs as part of a deploy -
+// Target's PreDeploy, PostDeploy, and HealthCheck hooks all use this shape.
+type CommandSpec struct {
+	Args       []string `json:"args"`
+	TimeoutSec int      `json:"timeout_sec"`
+	WorkingDir string   `json:"working_dir"`
+}
+
+const defaultHookTimeout = 30 * time.Second
+
+func (c *CommandSpec) timeout() time.Duration {
+	if c == nil || c.TimeoutSec <= 0 {
+		return defaultHookTimeout
+	}
+	return time.Duration(c.TimeoutSec) * time.Second
+}
+
+// hookEnv is the set of UPDATER_* variables every PreDeploy/PostDeploy/
+// HealthCheck hook receives, on top of the updater process's own
+// environment.
+func hookEnv(target *Target, releaseDir, releaseID, prevReleaseID string) []string {
+	return append(os.Environ(),
+		"UPDATER_RELEASE_DIR="+releaseDir,
+		"UPDATER_RELEASE_ID="+releaseID,
+		"UPDATER_PREV_RELEASE_ID="+prevReleaseID,
+		"UPDATER_TARGET_NAME="+target.Name,
+	)
+}
+
+// runHook runs spec (a no-op if spec is nil or empty), logging its combined
+// stdout/stderr under label and returning an error if it exits non-zero or
+// its timeout expires.
+func runHook(ctx context.Context, label string, spec *CommandSpec, env []string) error {
+	if spec == nil || len(spec.Args) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, spec.timeout())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, spec.Args[0], spec.Args[1:]...)
+	cmd.Dir = spec.WorkingDir
+	cmd.Env = env
+
+	output, err := cmd.CombinedOutput()
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if line != "" {
+			log.Printf("%s: %s", label, line)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", label, err)
+	}
+	return nil
+}
+
+// runHealthCheck runs target.HealthCheck (a no-op if unset) up to
+// HealthCheckRetries times with HealthCheckBackoffSec between attempts,
+// succeeding as soon as one attempt does.
+func runHealthCheck(ctx context.Context, target *Target, env []string) error {
+	if target.HealthCheck == nil || len(target.HealthCheck.Args) == 0 {
+		return nil
+	}
+
+	retries := target.HealthCheckRetries
+	if retries <= 0 {
+		retries = 1
+	}
+	backoff := time.Duration(target.HealthCheckBackoffSec) * time.Second
+	if backoff <= 0 {
+		backoff = 2 * time.Second
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= retries; attempt++ {
+		label := fmt.Sprintf("%s: health check (attempt %d/%d)", target.Name, attempt, retries)
+		if lastErr = runHook(ctx, label, target.HealthCheck, env); lastErr == nil {
+			return nil
+		}
+		if attempt < retries {
+			time.Sleep(backoff)
+		}
+	}
+	return fmt.Errorf("did not become healthy after %d attempts: %v", retries, lastErr)
+}
+
+// deployWithHealthCheck extracts and activates a release the way
+// deployRelease always did, then wraps that swap with the target's
+// pre/post-deploy hooks and health check, turning it into a transactional
+// swap-or-revert operation: if the post-deploy hook or health check never
+// succeeds, the symlink is restored to lastReleaseID and the new release
+// directory is removed. Old releases are only pruned down to
+// config.RetainReleases once the new one has passed both checks, so
+// lastReleaseID's directory is still there for rollbackFailedDeploy (or a
+// manual `rollback`) to fall back to.
+func deployWithHealthCheck(ctx context.Context, config *Config, target *Target, releaseID, lastReleaseID string, missingLastRelease bool, tarGzBytes []byte) error {
+	releaseDir := getReleaseDir(config.DeployDir, target.Name, releaseID)
+	env := hookEnv(target, releaseDir, releaseID, lastReleaseID)
+
+	if err := runHook(ctx, target.Name+": pre-deploy", target.PreDeploy, env); err != nil {
+		return err
+	}
+
+	if err := deployRelease(config.DeployDir, target.Name, releaseID, tarGzBytes); err != nil {
+		return err
+	}
+
+	if err := runHook(ctx, target.Name+": post-deploy", target.PostDeploy, env); err != nil {
+		return rollbackFailedDeploy(target, config, releaseID, lastReleaseID, missingLastRelease, err)
+	}
+
+	if err := runHealthCheck(ctx, target, env); err != nil {
+		return rollbackFailedDeploy(target, config, releaseID, lastReleaseID, missingLastRelease, err)
+	}
+
+	if err := pruneOldReleases(config.DeployDir, target.Name, config.RetainReleases); err != nil {
+		log.Printf("%s: failed to prune old releases: %v", target.Name, err)
+	}
+
+	return nil
+}
+
+// rollbackFailedDeploy restores the symlink to lastReleaseID (leaving it
+// alone if this was the target's first-ever deploy, since there's nothing
+// to restore it to) and removes the release directory that just failed its
+// checks, so a bad release doesn't linger half-activated. It also pins
+// releaseID as AvoidReleaseID, the same way a manual rollback does, so the
+// polling loop doesn't immediately see it as "latest" again and redeploy it
+// in a tight, ever-failing loop.
+func rollbackFailedDeploy(target *Target, config *Config, releaseID, lastReleaseID string, missingLastRelease bool, cause error) error {
+	log.Printf("%s: release %s failed post-deploy checks, rolling back: %v", target.Name, releaseID, cause)
+
+	if !missingLastRelease {
+		lastReleaseDir := getReleaseDir(config.DeployDir, target.Name, lastReleaseID)
+		if err := atomicSymlink(lastReleaseDir, getReleaseSymlink(config.DeployDir, target.Name)); err != nil {
+			log.Printf("%s: failed to restore symlink to release %s: %v", target.Name, lastReleaseID, err)
+		}
+	}
+
+	if err := os.RemoveAll(getReleaseDir(config.DeployDir, target.Name, releaseID)); err != nil {
+		log.Printf("%s: failed to remove failed release dir: %v", target.Name, err)
+	}
+
+	if err := writeRolloutState(config.DeployDir, target.Name, &rolloutState{AvoidReleaseID: releaseID}); err != nil {
+		log.Printf("%s: failed to record rollback state: %v", target.Name, err)
+	}
+
+	return fmt.Errorf("deploy failed post-deploy checks: %v", cause)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// rolloutState is the per-target state.json written alongside the release
+// symlink. Its only job today is recording a rollback pin: once set,
+// AvoidReleaseID keeps the polling loop from immediately redeploying the
+// release an operator just rolled away from, until either the pin is
+// cleared or a different (presumably newer) release shows up.
+type rolloutState struct {
+	AvoidReleaseID string `json:"avoid_release_id"`
+}
+
+func stateFilePath(deployDir, targetName string) string {
+	return filepath.Join(deployDir, targetName+".state.json")
+}
+
+func readRolloutState(deployDir, targetName string) (*rolloutState, error) {
+	b, err := os.ReadFile(stateFilePath(deployDir, targetName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &rolloutState{}, nil
+		}
+		return nil, err
+	}
+	state := &rolloutState{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func writeRolloutState(deployDir, targetName string, state *rolloutState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(stateFilePath(deployDir, targetName), b, 0644)
+}
+
+func clearRolloutState(deployDir, targetName string) error {
+	err := os.Remove(stateFilePath(deployDir, targetName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// releaseIDFromDirName extracts the release ID suffix from a
+// "<targetName>-<releaseID>" directory name.
+func releaseIDFromDirName(targetName, dirName string) (string, bool) {
+	prefix := targetName + "-"
+	if !strings.HasPrefix(dirName, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(dirName, prefix), true
+}
+
+// listReleaseDirNames returns deployDir's "<targetName>-*" directory names,
+// newest first by modification time.
+func listReleaseDirNames(deployDir, targetName string) ([]string, error) {
+	entries, err := os.ReadDir(deployDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, ok := releaseIDFromDirName(targetName, entry.Name()); ok {
+			names = append(names, entry.Name())
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		fi, errI := os.Stat(filepath.Join(deployDir, names[i]))
+		fj, errJ := os.Stat(filepath.Join(deployDir, names[j]))
+		if errI != nil || errJ != nil {
+			return names[i] > names[j]
+		}
+		return fi.ModTime().After(fj.ModTime())
+	})
+	return names, nil
+}
+
+// pruneOldReleases removes deployDir/<targetName>-* directories beyond the
+// retain most recent (by modification time), so old releases accumulate
+// bounded instead of disappearing the instant a new one lands.
+func pruneOldReleases(deployDir, targetName string, retain int) error {
+	names, err := listReleaseDirNames(deployDir, targetName)
+	if err != nil {
+		return err
+	}
+	if retain < 1 || len(names) <= retain {
+		return nil
+	}
+
+	for _, name := range names[retain:] {
+		if err := os.RemoveAll(filepath.Join(deployDir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// previousRetainedReleaseID returns the release ID immediately before
+// currentReleaseID in the retained, newest-first release directory list -
+// i.e. the one `rollback` falls back to when -to isn't given.
+func previousRetainedReleaseID(deployDir, targetName, currentReleaseID string) (string, error) {
+	names, err := listReleaseDirNames(deployDir, targetName)
+	if err != nil {
+		return "", err
+	}
+
+	for i, name := range names {
+		id, _ := releaseIDFromDirName(targetName, name)
+		if id == currentReleaseID && i+1 < len(names) {
+			prevID, _ := releaseIDFromDirName(targetName, names[i+1])
+			return prevID, nil
+		}
+	}
+	return "", fmt.Errorf("no retained release to roll back to for target %q", targetName)
+}
+
+// atomicSymlink points symlinkPath at target using the same tmp-then-rename
+// trick deployRelease uses, so a crash mid-rollback can never leave the
+// symlink missing or half-written.
+func atomicSymlink(target, symlinkPath string) error {
+	tmpPath := symlinkPath + ".tmp"
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, symlinkPath)
+}
+
+// runRollback implements `updater rollback -target <name> [-to <releaseID>]`.
+func runRollback(args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	configFile := fs.String("config", "config.json", "path to config file")
+	targetName := fs.String("target", "", "target to roll back (required)")
+	toReleaseID := fs.String("to", "", "release ID to roll back to (defaults to the previous retained release)")
+	fs.Parse(args)
+
+	if *targetName == "" {
+		return fmt.Errorf("-target is required")
+	}
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		return fmt.Errorf("loading config: %v", err)
+	}
+
+	rolledBackTo, err := performRollback(config, *targetName, *toReleaseID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%s: rolled back to release %s", *targetName, rolledBackTo)
+	return nil
+}
+
+// performRollback re-points target's release symlink at toReleaseID (or, if
+// toReleaseID is empty, the previous retained release), recording the
+// rollback in state.json so the polling loop won't immediately redeploy the
+// release just rolled away from. It returns the release ID rolled back to.
+// Both runRollback and the /rollback HTTP endpoint share this.
+func performRollback(config *Config, targetName, toReleaseID string) (string, error) {
+	target := config.findTarget(targetName)
+	if target == nil {
+		return "", fmt.Errorf("unknown target %q", targetName)
+	}
+
+	if toReleaseID != "" {
+		if err := validateReleaseID(toReleaseID); err != nil {
+			return "", err
+		}
+	}
+
+	currentReleaseID, err := getLastReleaseID(config.DeployDir, target.Name)
+	if err != nil {
+		return "", fmt.Errorf("getting current release: %v", err)
+	}
+
+	releaseID := toReleaseID
+	if releaseID == "" {
+		releaseID, err = previousRetainedReleaseID(config.DeployDir, target.Name, currentReleaseID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	releaseDir := getReleaseDir(config.DeployDir, target.Name, releaseID)
+	if _, err := os.Stat(releaseDir); err != nil {
+		return "", fmt.Errorf("release %s is not available locally: %v", releaseID, err)
+	}
+
+	if err := atomicSymlink(releaseDir, getReleaseSymlink(config.DeployDir, target.Name)); err != nil {
+		return "", fmt.Errorf("updating symlink: %v", err)
+	}
+
+	if err := writeRolloutState(config.DeployDir, target.Name, &rolloutState{AvoidReleaseID: currentReleaseID}); err != nil {
+		return "", fmt.Errorf("recording rollback state: %v", err)
+	}
+
+	return releaseID, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetReleaseDirDoesNotSanitizeReleaseID(t *testing.T) {
+	// getReleaseDir itself does no sanitization - that's validateReleaseID's
+	// job, called on every path into it. This test documents why: a
+	// traversal-shaped release ID walks straight out of deployDir.
+	got := getReleaseDir("/var/lib/updater/releases", "myapp", "../../../../../../tmp/pwn")
+	if got != "/tmp/pwn" {
+		t.Fatalf("getReleaseDir() = %q, want it to demonstrate escaping deployDir", got)
+	}
+}
+
+func TestValidateReleaseIDRejectsUnsafeIDs(t *testing.T) {
+	for _, id := range []string{
+		"../../../../../../tmp/pwn",
+		"..",
+		"foo/bar",
+		"foo/../bar",
+		"",
+	} {
+		if err := validateReleaseID(id); err == nil {
+			t.Errorf("validateReleaseID(%q) = nil, want error", id)
+		}
+	}
+}
+
+func TestValidateReleaseIDAllowsOrdinaryIDs(t *testing.T) {
+	for _, id := range []string{"v1.2.3", "1234567", "release-42", "1.0_beta"} {
+		if err := validateReleaseID(id); err != nil {
+			t.Errorf("validateReleaseID(%q) = %v, want nil", id, err)
+		}
+	}
+}
+
+func TestPreviousRetainedReleaseID(t *testing.T) {
+	dir := t.TempDir()
+	for _, id := range []string{"1", "2", "3"} {
+		if err := os.Mkdir(filepath.Join(dir, "myapp-"+id), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+
+	names, err := listReleaseDirNames(dir, "myapp")
+	if err != nil {
+		t.Fatalf("listReleaseDirNames: %v", err)
+	}
+	if len(names) != 3 {
+		t.Fatalf("listReleaseDirNames() = %v, want 3 entries", names)
+	}
+
+	// previousRetainedReleaseID walks the newest-first list and returns the
+	// entry right after currentReleaseID - exercise it with the first entry
+	// as current and expect the second, taking both from the listed order
+	// rather than assuming modtime order, since directories created in the
+	// same test can share a timestamp.
+	currentID, _ := releaseIDFromDirName("myapp", names[0])
+	wantPrevID, _ := releaseIDFromDirName("myapp", names[1])
+
+	gotPrevID, err := previousRetainedReleaseID(dir, "myapp", currentID)
+	if err != nil {
+		t.Fatalf("previousRetainedReleaseID: %v", err)
+	}
+	if gotPrevID != wantPrevID {
+		t.Errorf("previousRetainedReleaseID() = %q, want %q", gotPrevID, wantPrevID)
+	}
+
+	if _, err := previousRetainedReleaseID(dir, "myapp", "does-not-exist"); err == nil {
+		t.Error("previousRetainedReleaseID(unknown current) = nil error, want error")
+	}
+}
+
+func TestPerformRollbackRejectsUnsafeTo(t *testing.T) {
+	dir := t.TempDir()
+	config := &Config{
+		DeployDir: dir,
+		Targets:   []*Target{{Name: "myapp", Owner: "o", Repo: "r"}},
+	}
+
+	if _, err := performRollback(config, "myapp", "../../etc/passwd"); err == nil {
+		t.Fatal("performRollback() = nil error, want rejection of unsafe -to value")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// targetStatus is the per-target state the /status endpoint reports. The
+// polling loop updates it as it runs; the control server reads it and sets
+// ReleaseID after a rollback, so all access goes through the mutex.
+type targetStatus struct {
+	mu sync.Mutex
+
+	ReleaseID     string    `json:"release_id,omitempty"`
+	LastCheckTime time.Time `json:"last_check_time,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextCheckTime time.Time `json:"next_check_time,omitempty"`
+}
+
+func (s *targetStatus) set(fn func(*targetStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s)
+}
+
+func (s *targetStatus) snapshot() targetStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return targetStatus{
+		ReleaseID:     s.ReleaseID,
+		LastCheckTime: s.LastCheckTime,
+		LastError:     s.LastError,
+		NextCheckTime: s.NextCheckTime,
+	}
+}
+
+// controlServer implements the operator-facing HTTP API (GET /status,
+// POST /check, POST /rollback, GET /healthz) described on Config.ListenAddr.
+// It shares each target's targetStatus and trigger channel with the
+// polling loop started in runDaemon.
+type controlServer struct {
+	config   *Config
+	statuses map[string]*targetStatus
+	triggers map[string]chan struct{}
+	token    string
+}
+
+func newControlServer(config *Config, statuses map[string]*targetStatus, triggers map[string]chan struct{}) *controlServer {
+	return &controlServer{
+		config:   config,
+		statuses: statuses,
+		triggers: triggers,
+		token:    os.Getenv("UPDATER_CONTROL_TOKEN"),
+	}
+}
+
+func (s *controlServer) mux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/check", s.handleCheck)
+	mux.HandleFunc("/rollback", s.handleRollback)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	return mux
+}
+
+// requireAuth enforces the UPDATER_CONTROL_TOKEN bearer token on mutating
+// endpoints, if one is configured; it's a no-op (always authorized) when
+// the environment variable isn't set.
+func (s *controlServer) requireAuth(w http.ResponseWriter, r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	want := "Bearer " + s.token
+	got := r.Header.Get("Authorization")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1 {
+		return true
+	}
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+	return false
+}
+
+func (s *controlServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	out := make(map[string]targetStatus, len(s.statuses))
+	for name, status := range s.statuses {
+		out[name] = status.snapshot()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *controlServer) handleCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAuth(w, r) {
+		return
+	}
+
+	name := r.URL.Query().Get("target")
+	trigger, ok := s.triggers[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown target %q", name), http.StatusNotFound)
+		return
+	}
+
+	select {
+	case trigger <- struct{}{}:
+	default:
+		// a check is already pending for this target; no need to queue another
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *controlServer) handleRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAuth(w, r) {
+		return
+	}
+
+	name := r.URL.Query().Get("target")
+	to := r.URL.Query().Get("to")
+
+	rolledBackTo, err := performRollback(s.config, name, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if status, ok := s.statuses[name]; ok {
+		status.set(func(s *targetStatus) { s.ReleaseID = rolledBackTo })
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"target": name, "release_id": rolledBackTo})
+}
+
+func (s *controlServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAuth(t *testing.T) {
+	s := &controlServer{token: "secret-token"}
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"correct token", "Bearer secret-token", true},
+		{"missing header", "", false},
+		{"wrong token", "Bearer wrong-token", false},
+		{"wrong scheme", "Basic secret-token", false},
+		{"token as prefix of header value", "Bearer secret-tokenX", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/check", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			got := s.requireAuth(w, req)
+			if got != tt.want {
+				t.Errorf("requireAuth() = %v, want %v", got, tt.want)
+			}
+			if !tt.want && w.Code != 401 {
+				t.Errorf("requireAuth() left status %d, want 401", w.Code)
+			}
+		})
+	}
+}
+
+func TestRequireAuthNoTokenConfigured(t *testing.T) {
+	s := &controlServer{token: ""}
+	req := httptest.NewRequest("POST", "/check", nil)
+	w := httptest.NewRecorder()
+
+	if !s.requireAuth(w, req) {
+		t.Error("requireAuth() = false, want true when no token is configured")
+	}
+}
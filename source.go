@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Release is the source-agnostic view of a published release that the rest
+// of the updater operates on, regardless of which forge (or plain HTTPS
+// manifest) it came from.
+type Release struct {
+	ID     string
+	Tag    string
+	Assets []Asset
+}
+
+// Asset is one downloadable file attached to a Release. DownloadURL is
+// enough for most sources; Owner/Repo/ID are only populated by sources
+// (currently GitHub) whose Download implementation needs to go back through
+// an authenticated API call rather than fetching DownloadURL directly.
+type Asset struct {
+	Name        string
+	DownloadURL string
+
+	Owner string
+	Repo  string
+	ID    int64
+}
+
+// ReleaseSource abstracts over where a target's releases are published, so
+// the polling loop and asset-selection logic don't need to know whether
+// they're talking to GitHub, GitLab, Gitea, or a self-hosted manifest.
+type ReleaseSource interface {
+	// LatestRelease returns the most recent release for target.
+	LatestRelease(ctx context.Context, target *Target) (*Release, error)
+	// Download fetches the bytes of an asset previously returned by
+	// LatestRelease.
+	Download(ctx context.Context, asset *Asset) ([]byte, error)
+}
+
+// newReleaseSource builds the ReleaseSource that target.Source selects,
+// reading its credentials (if any) from the environment variable named by
+// target.TokenEnv.
+func newReleaseSource(target *Target) (ReleaseSource, error) {
+	tokenEnv := target.TokenEnv
+	if tokenEnv == "" && (target.Source == "" || target.Source == "github") {
+		// Preserve the original single-source behavior, where the GitHub
+		// token came from a fixed, well-known environment variable.
+		tokenEnv = "GITHUB_API_TOKEN"
+	}
+	var token string
+	if tokenEnv != "" {
+		token = os.Getenv(tokenEnv)
+	}
+
+	switch target.Source {
+	case "", "github":
+		return newGitHubSource(target.BaseURL, token), nil
+	case "gitlab":
+		return newGitLabSource(target.BaseURL, token)
+	case "gitea":
+		return newGiteaSource(target.BaseURL, token), nil
+	case "https":
+		return newHTTPSSource(target.BaseURL, token), nil
+	default:
+		return nil, fmt.Errorf("target %q: unknown source %q", target.Name, target.Source)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// giteaSource talks to a Gitea or Forgejo instance's REST API directly;
+// neither project has an official Go client worth depending on for a
+// single endpoint.
+type giteaSource struct {
+	baseURL string
+	token   string
+}
+
+func newGiteaSource(baseURL, token string) *giteaSource {
+	return &giteaSource{baseURL: strings.TrimSuffix(baseURL, "/"), token: token}
+}
+
+type giteaRelease struct {
+	ID      int64        `json:"id"`
+	TagName string       `json:"tag_name"`
+	Assets  []giteaAsset `json:"assets"`
+}
+
+type giteaAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+func (s *giteaSource) LatestRelease(ctx context.Context, target *Target) (*Release, error) {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/releases/latest", s.baseURL, target.Owner, target.Repo)
+	body, err := s.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var gr giteaRelease
+	if err := json.Unmarshal(body, &gr); err != nil {
+		return nil, fmt.Errorf("decoding release: %v", err)
+	}
+
+	r := &Release{
+		ID:  strconv.FormatInt(gr.ID, 10),
+		Tag: gr.TagName,
+	}
+	for _, a := range gr.Assets {
+		r.Assets = append(r.Assets, Asset{Name: a.Name, DownloadURL: a.BrowserDownloadURL})
+	}
+	return r, nil
+}
+
+func (s *giteaSource) Download(ctx context.Context, asset *Asset) ([]byte, error) {
+	return s.get(ctx, asset.DownloadURL)
+}
+
+func (s *giteaSource) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "token "+s.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v fetching %v", resp.Status, url)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// gitHubSource is the original ReleaseSource this updater shipped with,
+// now behind the ReleaseSource interface instead of wired directly into
+// the polling loop.
+type gitHubSource struct {
+	client *github.Client
+}
+
+func newGitHubSource(baseURL, token string) *gitHubSource {
+	client := github.NewClient(nil)
+	if token != "" {
+		client = client.WithAuthToken(token)
+	}
+	if baseURL != "" {
+		if enterpriseClient, err := client.WithEnterpriseURLs(baseURL, baseURL); err == nil {
+			client = enterpriseClient
+		}
+	}
+	return &gitHubSource{client: client}
+}
+
+func (s *gitHubSource) LatestRelease(ctx context.Context, target *Target) (*Release, error) {
+	release, _, err := s.client.Repositories.GetLatestRelease(ctx, target.Owner, target.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Release{
+		ID:  strconv.FormatInt(release.GetID(), 10),
+		Tag: release.GetTagName(),
+	}
+	for _, a := range release.Assets {
+		r.Assets = append(r.Assets, Asset{
+			Name:        a.GetName(),
+			DownloadURL: a.GetBrowserDownloadURL(),
+			Owner:       target.Owner,
+			Repo:        target.Repo,
+			ID:          a.GetID(),
+		})
+	}
+	return r, nil
+}
+
+// Download fetches asset through the GitHub API by ID rather than GETing
+// DownloadURL directly: browser_download_url 404s for private repos even
+// with a valid token, since it's unauthenticated. Passing a nil
+// follow-redirects client makes the SDK send the authenticated request
+// itself (Accept: application/octet-stream, bearer token) and hand back the
+// signed redirect URL instead of following it, so we can validate that URL
+// before fetching it ourselves.
+func (s *gitHubSource) Download(ctx context.Context, asset *Asset) ([]byte, error) {
+	rc, redirectURL, err := s.client.Repositories.DownloadReleaseAsset(ctx, asset.Owner, asset.Repo, asset.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rc != nil {
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+
+	if err := validateAssetURL(redirectURL); err != nil {
+		return nil, fmt.Errorf("asset redirect URL validation failed: %v", err)
+	}
+	return downloadAsset(redirectURL)
+}
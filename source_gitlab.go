@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// gitLabSource talks to a GitLab (or self-managed GitLab) instance using a
+// project access token, so private-repo releases work the same as public
+// ones.
+type gitLabSource struct {
+	client *gitlab.Client
+	token  string
+}
+
+func newGitLabSource(baseURL, token string) (*gitLabSource, error) {
+	var opts []gitlab.ClientOptionFunc
+	if baseURL != "" {
+		opts = append(opts, gitlab.WithBaseURL(baseURL))
+	}
+	client, err := gitlab.NewClient(token, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating gitlab client: %v", err)
+	}
+	return &gitLabSource{client: client, token: token}, nil
+}
+
+func (s *gitLabSource) LatestRelease(ctx context.Context, target *Target) (*Release, error) {
+	projectID := target.Owner + "/" + target.Repo
+	releases, _, err := s.client.Releases.ListReleases(projectID, &gitlab.ListReleasesOptions{
+		OrderBy: gitlab.Ptr("released_at"),
+		Sort:    gitlab.Ptr("desc"),
+		ListOptions: gitlab.ListOptions{
+			PerPage: 1,
+		},
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("listing releases: %v", err)
+	}
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("project %s has no releases", projectID)
+	}
+	release := releases[0]
+
+	r := &Release{
+		ID:  release.TagName,
+		Tag: release.TagName,
+	}
+	for _, link := range release.Assets.Links {
+		r.Assets = append(r.Assets, Asset{Name: link.Name, DownloadURL: link.URL})
+	}
+	return r, nil
+}
+
+func (s *gitLabSource) Download(ctx context.Context, asset *Asset) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.DownloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", s.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v downloading %v", resp.Status, asset.DownloadURL)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// httpsSource fetches a self-hosted JSON manifest instead of talking to any
+// forge's release API, for users who don't want to run one just to publish
+// update metadata. The manifest is expected at BaseURL and shaped as:
+//
+//	{"version": "1.2.3", "asset_url": "...", "sig_url": "..."}
+//
+// The archive and signature are surfaced as assets named "archive" and
+// "signature"; a target using this source should set AssetPattern to
+// "^archive$" and SignaturePattern to "^signature$"; the repo-name-based
+// default patterns never match these names.
+type httpsSource struct {
+	manifestURL string
+	token       string
+}
+
+func newHTTPSSource(manifestURL, token string) *httpsSource {
+	return &httpsSource{manifestURL: manifestURL, token: token}
+}
+
+type httpsManifest struct {
+	Version  string `json:"version"`
+	AssetURL string `json:"asset_url"`
+	SigURL   string `json:"sig_url"`
+}
+
+func (s *httpsSource) LatestRelease(ctx context.Context, target *Target) (*Release, error) {
+	body, err := s.get(ctx, s.manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching manifest: %v", err)
+	}
+
+	var m httpsManifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %v", err)
+	}
+
+	return &Release{
+		ID:  m.Version,
+		Tag: m.Version,
+		Assets: []Asset{
+			{Name: "archive", DownloadURL: m.AssetURL},
+			{Name: "signature", DownloadURL: m.SigURL},
+		},
+	}, nil
+}
+
+func (s *httpsSource) Download(ctx context.Context, asset *Asset) ([]byte, error) {
+	return s.get(ctx, asset.DownloadURL)
+}
+
+func (s *httpsSource) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v fetching %v", resp.Status, url)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -12,99 +11,203 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/go-github/v57/github"
 	"github.com/jedisct1/go-minisign"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "rollback" {
+		if err := runRollback(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	runDaemon()
+}
+
+func runDaemon() {
 	configFile := flag.String("config", "config.json", "path to config file")
 	flag.Parse()
 
-	b, err := os.ReadFile(*configFile)
+	config, err := loadConfig(*configFile)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	config := Config{}
-	err = json.Unmarshal(b, &config)
-	if err != nil {
-		log.Fatal(err)
+	sources := make(map[string]ReleaseSource, len(config.Targets))
+	statuses := make(map[string]*targetStatus, len(config.Targets))
+	triggers := make(map[string]chan struct{}, len(config.Targets))
+	for _, target := range config.Targets {
+		source, err := newReleaseSource(target)
+		if err != nil {
+			log.Fatalf("%s: %v", target.Name, err)
+		}
+		sources[target.Name] = source
+		statuses[target.Name] = &targetStatus{}
+		triggers[target.Name] = make(chan struct{}, 1)
 	}
 
-	err = config.Validate()
-	if err != nil {
-		log.Fatal(err)
+	if config.ListenAddr != "" {
+		server := newControlServer(config, statuses, triggers)
+		go func() {
+			log.Printf("control API listening on %s", config.ListenAddr)
+			if err := http.ListenAndServe(config.ListenAddr, server.mux()); err != nil {
+				log.Fatalf("control API: %v", err)
+			}
+		}()
 	}
 
-	githubAPIToken, ok := os.LookupEnv("GITHUB_API_TOKEN")
-	if !ok || githubAPIToken == "" {
-		log.Fatal("GITHUB_API_TOKEN environment variable must be set and non-empty")
+	var wg sync.WaitGroup
+	for _, target := range config.Targets {
+		wg.Add(1)
+		go func(target *Target) {
+			defer wg.Done()
+			runTargetLoop(config, target, sources[target.Name], statuses[target.Name], triggers[target.Name])
+		}(target)
 	}
-	client := github.NewClient(nil).WithAuthToken(githubAPIToken)
+	wg.Wait()
+}
+
+// runTargetLoop checks target for updates on config.UpdateInterval, woken
+// early whenever something sends on trigger (the control API's POST
+// /check). It never returns.
+func runTargetLoop(config *Config, target *Target, source ReleaseSource, status *targetStatus, trigger chan struct{}) {
+	interval := time.Duration(config.UpdateInterval) * time.Second
+	timer := time.NewTimer(0) // check once immediately on startup
+	defer timer.Stop()
 
 	for {
-		for _, target := range config.Targets {
-			log.Printf("%s: checking for update...", target.Name)
-
-			ctx := context.Background()
-			release, _, err := client.Repositories.GetLatestRelease(ctx, target.Owner, target.Repo)
-			if err != nil {
-				log.Printf("%s: %v", target.Name, err)
-				continue
+		select {
+		case <-timer.C:
+		case <-trigger:
+			if !timer.Stop() {
+				<-timer.C
 			}
+		}
 
-			releaseID := strconv.FormatInt(*release.ID, 10)
-			missingLastRelease := false
-			lastReleaseID, err := getLastReleaseID(config.DeployDir, target.Name)
-			if err != nil {
-				if os.IsNotExist(err) {
-					missingLastRelease = true
-				} else {
-					log.Printf("%s: error getting last release ID: %v", target.Name, err)
-					continue
-				}
-			}
-			if !missingLastRelease && string(lastReleaseID) == releaseID {
-				log.Printf("%s: already at latest release", target.Name)
-				continue
-			}
+		checkTarget(config, target, source, status)
 
-			log.Printf("%s: update found", target.Name)
-			tarGzBytes, sigBytes, err := downloadReleaseAssets(target, release)
-			if err != nil {
-				log.Printf("%s: update failed: %v", target.Name, err)
-				continue
-			}
+		status.set(func(s *targetStatus) { s.NextCheckTime = time.Now().Add(interval) })
+		timer.Reset(interval)
+	}
+}
 
-			if !config.UnsafeSkipSignatureVerification {
-				ok, err := verifySignature(config.PublicSigningKey, tarGzBytes, sigBytes)
-				if !ok {
-					log.Printf("%s: update failed: %v", target.Name, err)
-					continue
-				}
-			} else {
-				log.Printf("%s: skipping signature verification!", target.Name)
-			}
+// checkTarget runs a single poll-and-maybe-deploy cycle for target,
+// recording its outcome in status.
+func checkTarget(config *Config, target *Target, source ReleaseSource, status *targetStatus) {
+	log.Printf("%s: checking for update...", target.Name)
+	status.set(func(s *targetStatus) { s.LastCheckTime = time.Now() })
+
+	fail := func(format string, args ...any) {
+		err := fmt.Errorf(format, args...)
+		log.Printf("%s: %v", target.Name, err)
+		status.set(func(s *targetStatus) { s.LastError = err.Error() })
+	}
+	succeed := func(releaseID string) {
+		status.set(func(s *targetStatus) { s.ReleaseID = releaseID; s.LastError = "" })
+	}
+
+	ctx := context.Background()
+	release, err := source.LatestRelease(ctx, target)
+	if err != nil {
+		fail("%v", err)
+		return
+	}
+
+	releaseID := release.ID
+	if err := validateReleaseID(releaseID); err != nil {
+		fail("update failed: release ID from source is unsafe: %v", err)
+		return
+	}
+
+	missingLastRelease := false
+	lastReleaseID, err := getLastReleaseID(config.DeployDir, target.Name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			missingLastRelease = true
+		} else {
+			fail("error getting last release ID: %v", err)
+			return
+		}
+	}
+	if !missingLastRelease && lastReleaseID == releaseID {
+		log.Printf("%s: already at latest release", target.Name)
+		succeed(releaseID)
+		return
+	}
+
+	state, err := readRolloutState(config.DeployDir, target.Name)
+	if err != nil {
+		fail("error reading rollout state: %v", err)
+		return
+	}
+	if state.AvoidReleaseID != "" {
+		if state.AvoidReleaseID == releaseID {
+			log.Printf("%s: latest release %s was just rolled back from; rollback pin is sticky, skipping", target.Name, releaseID)
+			succeed(lastReleaseID)
+			return
+		}
+		// A different release showed up since the rollback; the pin no
+		// longer applies.
+		if err := clearRolloutState(config.DeployDir, target.Name); err != nil {
+			log.Printf("%s: error clearing rollout state: %v", target.Name, err)
+		}
+	}
+
+	log.Printf("%s: update found", target.Name)
+	archiveBytes, archiveName, verifyBytes, sigBytes, err := downloadReleaseAssets(ctx, source, target, release)
+	if err != nil {
+		fail("update failed: %v", err)
+		return
+	}
 
-			err = deployRelease(config.DeployDir, target.Name, releaseID, lastReleaseID, tarGzBytes)
-			if err != nil {
-				log.Printf("%s update failed: %v", target.Name, err)
-				continue
+	if !config.UnsafeSkipSignatureVerification {
+		ok, err := verifySignature(config.PublicSigningKey, verifyBytes, sigBytes)
+		if !ok {
+			fail("update failed: %v", err)
+			return
+		}
+		if target.SignatureMode == "manifest" {
+			if err := verifyChecksumManifest(verifyBytes, archiveName, archiveBytes); err != nil {
+				fail("checksum verification failed: %v", err)
+				return
 			}
-			log.Printf("%s: update successful", target.Name)
 		}
-		time.Sleep(time.Duration(config.UpdateInterval) * time.Second)
+	} else {
+		log.Printf("%s: skipping signature verification!", target.Name)
+	}
+
+	if err := deployWithHealthCheck(ctx, config, target, releaseID, lastReleaseID, missingLastRelease, archiveBytes); err != nil {
+		fail("update failed: %v", err)
+		return
 	}
+	log.Printf("%s: update successful", target.Name)
+	succeed(releaseID)
 }
 
 func getReleaseDir(deployDir, targetName, releaseID string) string {
 	return filepath.Join(deployDir, targetName+"-"+releaseID)
 }
 
+// releaseIDRegex matches the safe charset Target.Name is restricted to in
+// config.go, plus ".". Release IDs end up directly in getReleaseDir, so anything
+// outside this charset - in particular "/" and ".." - could otherwise walk
+// the resulting path outside deployDir.
+var releaseIDRegex = regexp.MustCompile(`^[\w.-]+$`)
+
+// validateReleaseID rejects a release ID that isn't safe to use as a path
+// component, whether it came from a ReleaseSource's LatestRelease or from an
+// operator-supplied rollback target.
+func validateReleaseID(releaseID string) error {
+	if !releaseIDRegex.MatchString(releaseID) || strings.Contains(releaseID, "..") {
+		return fmt.Errorf("release ID %q is not a valid path component", releaseID)
+	}
+	return nil
+}
+
 func getReleaseSymlink(deployDir, targetName string) string {
 	return filepath.Join(deployDir, targetName)
 }
@@ -130,58 +233,83 @@ func getLastReleaseID(deployDir, targetName string) (string, error) {
 	return split[1], nil
 }
 
-func downloadReleaseAssets(target *Target, release *github.RepositoryRelease) (tarGzBytes, sigBytes []byte, err error) {
+// downloadReleaseAssets downloads the release's archive plus whatever the
+// signature covers: in the default "asset" SignatureMode that's the archive
+// itself (verifyBytes == archiveBytes); in "manifest" mode it's a separate
+// checksums file (see checksum.go) that verifyChecksumManifest then checks
+// archiveBytes against by name.
+func downloadReleaseAssets(ctx context.Context, source ReleaseSource, target *Target, release *Release) (archiveBytes []byte, archiveName string, verifyBytes, sigBytes []byte, err error) {
 	if len(release.Assets) < 2 {
 		err = fmt.Errorf("release needs at least 2 assets (have %v)", len(release.Assets))
 		return
 	}
 
-	const tarGzRegexFmt = `^%s-[\w.]+\.tar\.gz$`
-	const sigRegexFmt = `^%s-[\w.]+\.minisig$`
-	tarGzRegex := regexp.MustCompile(fmt.Sprintf(tarGzRegexFmt, target.Repo))
-	sigRegex := regexp.MustCompile(fmt.Sprintf(sigRegexFmt, target.Repo))
+	assetPattern := target.AssetPattern
+	if assetPattern == "" {
+		assetPattern = defaultAssetPattern
+	}
+	sigPattern := target.SignaturePattern
+	if sigPattern == "" {
+		sigPattern = defaultSignaturePattern
+	}
 
-	if !(tarGzRegex.MatchString(*release.Assets[0].Name)) {
-		err = fmt.Errorf("first asset doesn't have expected name (%v)", *release.Assets[0].Name)
+	archiveAsset, err := selectAsset(assetPattern, target, release.Tag, release.Assets)
+	if err != nil {
+		err = fmt.Errorf("selecting archive asset: %v", err)
 		return
 	}
-	if !(sigRegex.MatchString(*release.Assets[1].Name)) {
-		err = fmt.Errorf("second asset doesn't have expected name (%v)", *release.Assets[1].Name)
+	archiveName = archiveAsset.Name
+
+	sigAsset, err := selectAsset(sigPattern, target, release.Tag, release.Assets)
+	if err != nil {
+		err = fmt.Errorf("selecting signature asset: %v", err)
 		return
 	}
 
-	tarGzDownloadUrl := release.Assets[0].GetBrowserDownloadURL()
-	if err = validateAssetURL(tarGzDownloadUrl); err != nil {
-		err = fmt.Errorf("tar.gz URL validation failed: %v", err)
+	archiveBytes, err = source.Download(ctx, archiveAsset)
+	if err != nil {
+		err = fmt.Errorf("archive download failed: %v", err)
 		return
 	}
-	sigDownloadURL := release.Assets[1].GetBrowserDownloadURL()
-	if err = validateAssetURL(sigDownloadURL); err != nil {
-		err = fmt.Errorf("signature URL validation failed: %v", err)
+
+	sigBytes, err = source.Download(ctx, sigAsset)
+	if err != nil {
+		err = fmt.Errorf("signature download failed: %v", err)
 		return
 	}
 
-	tarGzBytes, err = downloadAsset(tarGzDownloadUrl)
-	if err != nil {
-		err = fmt.Errorf("tar.gz download failed: %v", err)
+	if target.SignatureMode != "manifest" {
+		verifyBytes = archiveBytes
 		return
 	}
 
-	sigBytes, err = downloadAsset(sigDownloadURL)
+	manifestPattern := target.ChecksumManifestPattern
+	if manifestPattern == "" {
+		manifestPattern = defaultChecksumManifestPattern
+	}
+	manifestAsset, err2 := selectAsset(manifestPattern, target, release.Tag, release.Assets)
+	if err2 != nil {
+		err = fmt.Errorf("selecting checksum manifest asset: %v", err2)
+		return
+	}
+	verifyBytes, err = source.Download(ctx, manifestAsset)
 	if err != nil {
-		err = fmt.Errorf("signature download failed: %v", err)
+		err = fmt.Errorf("checksum manifest download failed: %v", err)
 		return
 	}
 	return
 }
 
+// validateAssetURL only requires https now: GitHub's signed asset redirects
+// land on S3, GitLab/Gitea on their own hosts, so a fixed-hostname check no
+// longer makes sense across sources.
 func validateAssetURL(assetUrl string) error {
 	parsedURL, err := url.Parse(assetUrl)
 	if err != nil {
 		return err
 	}
-	if parsedURL.Hostname() != "github.com" {
-		return fmt.Errorf("asset has non-GitHub URL (%v)", assetUrl)
+	if parsedURL.Scheme != "https" {
+		return fmt.Errorf("asset URL does not use https (%v)", assetUrl)
 	}
 	return nil
 }
@@ -192,6 +320,12 @@ func downloadAsset(assetUrl string) ([]byte, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v downloading %v", resp.Status, assetUrl)
+	}
+	if resp.ContentLength == 0 {
+		return nil, fmt.Errorf("asset %v reported zero content length", assetUrl)
+	}
 	return io.ReadAll(resp.Body)
 }
 
@@ -207,23 +341,24 @@ func verifySignature(publicSigningKey string, tarGzBytes, sigBytes []byte) (bool
 	return pk.Verify(tarGzBytes, sig)
 }
 
-func deployRelease(deployDir, targetName, releaseID, lastReleaseID string, tarGzBytes []byte) error {
+// defaultMaxExtractedSize bounds the total decompressed size of a release
+// archive, guarding against zip/zstd bombs in a compromised or malicious
+// upstream release.
+const defaultMaxExtractedSize = 1 << 30 // 1 GiB
+
+// deployRelease extracts tarGzBytes and swaps the release symlink to point
+// at it. It does not prune old releases itself: deployWithHealthCheck does
+// that afterward, once the new release has actually passed its post-deploy
+// hook and health check, so a failed deploy still has lastReleaseID's
+// directory on disk to roll back to.
+func deployRelease(deployDir, targetName, releaseID string, tarGzBytes []byte) error {
 	releaseDir := getReleaseDir(deployDir, targetName, releaseID)
 	if err := os.Mkdir(releaseDir, 0755); err != nil {
 		return err
 	}
-	if err := extractTarGz(tarGzBytes, releaseDir, 1); err != nil {
-		return err
-	}
-
-	releaseSymlink := getReleaseSymlink(deployDir, targetName)
-	if err := os.Symlink(releaseDir, releaseSymlink+".tmp"); err != nil {
-		return err
-	}
-	if err := os.Rename(releaseSymlink+".tmp", releaseSymlink); err != nil {
+	if err := extractArchive(tarGzBytes, releaseDir, 1, defaultMaxExtractedSize); err != nil {
 		return err
 	}
 
-	// clean up last release dir
-	return os.RemoveAll(getReleaseDir(deployDir, targetName, lastReleaseID))
+	return atomicSymlink(releaseDir, getReleaseSymlink(deployDir, targetName))
 }